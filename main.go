@@ -1,13 +1,22 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"crypto/rand"
 	"encoding/csv"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
+	"math"
+	"net"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,38 +27,346 @@ import (
 
 // 配置文件结构体
 type Config struct {
-	Domains       []string      `yaml:"domains"`
-	DNSServers    []string      `yaml:"dns_servers"`
-	TestCount     int           `yaml:"test_count"`
-	QueryInterval time.Duration `yaml:"query_interval"`
-	Concurrency   int           `yaml:"concurrency"` // 新增并发数字段
-	LogToFile     bool          `yaml:"log_to_file"` // 控制是否将日志写入文件
-	SaveCsv       bool          `yaml:"save_csv"`    // 控制是否保存CSV文件
-	TestRounds    int           `yaml:"test_rounds"` // 新增多轮测试字段
+	Domains            []string      `yaml:"domains"`
+	QueryTypes         []string      `yaml:"query_types"` // 新增查询记录类型字段，与domains做叉乘，如 [A, AAAA, MX]
+	DNSServers         []string      `yaml:"dns_servers"`
+	TestCount          int           `yaml:"test_count"`
+	QueryInterval      time.Duration `yaml:"query_interval"`
+	Concurrency        int           `yaml:"concurrency"` // 新增并发数字段
+	LogToFile          bool          `yaml:"log_to_file"` // 控制是否将日志写入文件
+	SaveCsv            bool          `yaml:"save_csv"`    // 控制是否保存CSV文件
+	TestRounds         int           `yaml:"test_rounds"` // 新增多轮测试字段
+	InsecureSkipVerify bool          `yaml:"insecure_skip_verify"` // 是否跳过DoT/DoH/DoQ证书校验
+	Bootstrap          []string      `yaml:"bootstrap"`            // 用于解析DoH/DoT/DoQ主机名的引导DNS服务器
+	ServerIPAddrs      []string      `yaml:"server_ip_addrs"`      // 预先指定上游服务器IP，跳过引导解析
+	HTTPVersions       []string      `yaml:"http_versions"`        // DoH使用的HTTP版本，如 ["2", "3"]，默认为1.1
 
+	Mode                string   `yaml:"mode"`                  // 运行模式："benchmark"（默认）或 "enumerate"
+	EnumerateBaseDomain string   `yaml:"enumerate_base_domain"` // 子域名枚举模式下的基础域名
+	WordlistFile        string   `yaml:"wordlist_file"`         // 子域名枚举模式下使用的字典文件，每行一个标签
+	EnumerateTypes      []string `yaml:"enumerate_types"`       // 子域名枚举模式下查询的记录类型，默认A、AAAA、CNAME
+
+	Edns0UDPSize uint16 `yaml:"edns0_udp_size"` // EDNS0通告的UDP缓冲区大小，默认4096
+	DnssecOk     bool   `yaml:"dnssec_ok"`      // 是否设置DO位以请求DNSSEC验证
+	EcsSubnet    string `yaml:"ecs_subnet"`     // EDNS Client Subnet携带的IP/掩码，如 "1.2.3.0/24"
+
+	Selection *SelectionConfig `yaml:"selection"` // 配置后，在所有轮次结束后输出排名靠前的DNS服务器
+}
+
+// SelectionConfig 控制测试结束后对DNS服务器/候选IP的排名与筛选
+type SelectionConfig struct {
+	TopN    int      `yaml:"top_n"`   // 输出排名前N的服务器
+	Metric  string   `yaml:"metric"`  // 排名依据的指标："mean"、"p50"、"p90"、"p95"（默认）、"p99"
+	MaxLoss *float64 `yaml:"max_loss"` // 允许的最大丢包率，超过则排除该服务器；未配置时不按丢包率过滤，0表示只保留零丢包的服务器
+	Output  string   `yaml:"output"`  // 排名结果输出文件路径，默认 best_servers.yaml
+}
+
+// httpVersionByName 配置中http_versions字符串到upstream.HTTPVersion常量的映射
+var httpVersionByName = map[string]upstream.HTTPVersion{
+	"1.1": upstream.HTTPVersion11,
+	"2":   upstream.HTTPVersion2,
+	"3":   upstream.HTTPVersion3,
+}
+
+// parseHTTPVersions 解析配置中的http_versions列表，未配置时默认为HTTP/1.1
+func parseHTTPVersions(versions []string) []upstream.HTTPVersion {
+	if len(versions) == 0 {
+		return []upstream.HTTPVersion{upstream.HTTPVersion11}
+	}
+	result := make([]upstream.HTTPVersion, 0, len(versions))
+	for _, v := range versions {
+		if hv, ok := httpVersionByName[strings.TrimSpace(v)]; ok {
+			result = append(result, hv)
+		}
+	}
+	if len(result) == 0 {
+		return []upstream.HTTPVersion{upstream.HTTPVersion11}
+	}
+	return result
+}
+
+// multiBootstrapResolver 依次尝试多个引导DNS服务器，直到有一个解析成功
+type multiBootstrapResolver struct {
+	resolvers []upstream.Resolver
+}
+
+func (m *multiBootstrapResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	var lastErr error
+	for _, r := range m.resolvers {
+		addrs, err := r.LookupNetIP(ctx, network, host)
+		if err == nil && len(addrs) > 0 {
+			return addrs, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("没有可用的引导解析器")
+	}
+	return nil, lastErr
+}
+
+// buildBootstrapResolver 根据配置构造Options.Bootstrap所需的Resolver：
+// 配置了server_ip_addrs时，用预先指定的IP构造静态解析器，跳过对服务器主机名的引导解析；
+// 否则，若配置了bootstrap，用这些引导DNS服务器构造一个按顺序尝试的解析器。
+func buildBootstrapResolver(cfg *Config) (upstream.Resolver, error) {
+	if len(cfg.ServerIPAddrs) > 0 {
+		addrs := make([]netip.Addr, 0, len(cfg.ServerIPAddrs))
+		for _, a := range cfg.ServerIPAddrs {
+			addr, err := netip.ParseAddr(strings.TrimSpace(a))
+			if err != nil {
+				continue
+			}
+			addrs = append(addrs, addr)
+		}
+		if len(addrs) == 0 {
+			return nil, nil
+		}
+		return upstream.StaticResolver(addrs), nil
+	}
+
+	if len(cfg.Bootstrap) == 0 {
+		return nil, nil
+	}
+	resolvers := make([]upstream.Resolver, 0, len(cfg.Bootstrap))
+	for _, addr := range cfg.Bootstrap {
+		r, err := upstream.NewUpstreamResolver(addr, &upstream.Options{Timeout: 5 * time.Second})
+		if err != nil {
+			return nil, fmt.Errorf("创建引导解析器失败(%s): %w", addr, err)
+		}
+		resolvers = append(resolvers, r)
+	}
+	return &multiBootstrapResolver{resolvers: resolvers}, nil
+}
+
+// detectProtocol 根据DNS服务器地址的URL scheme识别使用的协议，未带scheme时视为传统明文UDP。
+// 对于DoH，进一步根据配置的http_versions优先级区分DoH/2与DoH/3：该结果是根据配置推断出的
+// 预期协议，而非本次请求在连接上实际协商到的协议（dnsproxy的Resolver接口未暴露每次请求实际
+// 使用的HTTP版本），当客户端配置了多个版本且发生了回退时，记录的标签可能与实际协商结果不符。
+func detectProtocol(dnsServer string, httpVersions []upstream.HTTPVersion) string {
+	switch {
+	case strings.HasPrefix(dnsServer, "udp://"):
+		return "UDP"
+	case strings.HasPrefix(dnsServer, "tcp://"):
+		return "TCP"
+	case strings.HasPrefix(dnsServer, "tls://"):
+		return "DoT"
+	case strings.HasPrefix(dnsServer, "https://"):
+		for _, v := range httpVersions {
+			if v == upstream.HTTPVersion3 {
+				return "DoH/3"
+			}
+		}
+		for _, v := range httpVersions {
+			if v == upstream.HTTPVersion2 {
+				return "DoH/2"
+			}
+		}
+		return "DoH"
+	case strings.HasPrefix(dnsServer, "h3://"):
+		return "DoH/3"
+	case strings.HasPrefix(dnsServer, "quic://"):
+		return "DoQ"
+	case strings.HasPrefix(dnsServer, "sdns://"):
+		return "DNSCrypt"
+	default:
+		return "UDP"
+	}
+}
+
+// recordTypeByName 支持的记录类型名称到dns库类型常量的映射（不区分大小写）
+var recordTypeByName = map[string]uint16{
+	"A":     dns.TypeA,
+	"AAAA":  dns.TypeAAAA,
+	"MX":    dns.TypeMX,
+	"NS":    dns.TypeNS,
+	"TXT":   dns.TypeTXT,
+	"CNAME": dns.TypeCNAME,
+	"SRV":   dns.TypeSRV,
+}
+
+// parseRecordType 将记录类型名称（不区分大小写）解析为dns库的Qtype常量
+func parseRecordType(name string) (uint16, error) {
+	qtype, ok := recordTypeByName[strings.ToUpper(strings.TrimSpace(name))]
+	if !ok {
+		return 0, fmt.Errorf("不支持的记录类型: %s", name)
+	}
+	return qtype, nil
+}
+
+// splitDomainAndType 解析形如 "example.com:AAAA" 的域名条目，返回域名和可选的记录类型
+func splitDomainAndType(entry string) (domain string, qtype uint16, hasType bool) {
+	parts := strings.SplitN(entry, ":", 2)
+	if len(parts) == 2 {
+		if t, err := parseRecordType(parts[1]); err == nil {
+			return parts[0], t, true
+		}
+	}
+	return entry, 0, false
 }
 
 // DNS测试结果结构体
 type DnsTestResult struct {
-	Domain    string
-	Server    string
-	Timestamp time.Time
-	Latency   time.Duration
+	Domain     string
+	Server     string
+	RecordType uint16
+	Protocol   string // 根据服务器地址与http_versions配置推断的传输协议，如 UDP、TCP、DoT、DoH/2、DoH/3、DoQ、DNSCrypt；不是连接上实际协商到的协议
+	AD         bool   // 响应是否设置了AD（Authenticated Data）标志
+	AA         bool   // 响应是否设置了AA（Authoritative Answer）标志
+	ECSEchoed  bool   // resolver是否在响应中回显了ECS选项（未配置ecs_subnet时恒为false）
+	Timestamp  time.Time
+	Latency    time.Duration
+}
+
+// Stats 表示一组延时样本的统计信息
+type Stats struct {
+	Min       time.Duration
+	Max       time.Duration
+	Mean      time.Duration
+	StdDev    time.Duration
+	P50       time.Duration
+	P90       time.Duration
+	P95       time.Duration
+	P99       time.Duration
+	Jitter    time.Duration // 相邻样本延时绝对差的均值
+	LossRate  float64       // 失败次数 / 尝试次数
+	Attempted int
+	Succeeded int
+}
+
+// percentile 返回已排序延时切片中第p百分位的值（最近邻法，p取0-100）
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := p * len(sorted) / 100
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// computeStats 根据成功的延时样本与总尝试次数计算统计信息，delays为空时仅反映丢包率
+func computeStats(delays []time.Duration, attempted int) Stats {
+	stats := Stats{Attempted: attempted, Succeeded: len(delays)}
+	if attempted > 0 {
+		stats.LossRate = float64(attempted-len(delays)) / float64(attempted)
+	}
+	if len(delays) == 0 {
+		return stats
+	}
+
+	var sum time.Duration
+	for _, d := range delays {
+		sum += d
+	}
+	stats.Mean = sum / time.Duration(len(delays))
+
+	var varianceSum float64
+	for _, d := range delays {
+		diff := float64(d - stats.Mean)
+		varianceSum += diff * diff
+	}
+	stats.StdDev = time.Duration(math.Sqrt(varianceSum / float64(len(delays))))
+
+	if len(delays) >= 2 {
+		var jitterSum time.Duration
+		for i := 1; i < len(delays); i++ {
+			diff := delays[i] - delays[i-1]
+			if diff < 0 {
+				diff = -diff
+			}
+			jitterSum += diff
+		}
+		stats.Jitter = jitterSum / time.Duration(len(delays)-1)
+	}
+
+	sorted := make([]time.Duration, len(delays))
+	copy(sorted, delays)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	stats.Min = sorted[0]
+	stats.Max = sorted[len(sorted)-1]
+	stats.P50 = percentile(sorted, 50)
+	stats.P90 = percentile(sorted, 90)
+	stats.P95 = percentile(sorted, 95)
+	stats.P99 = percentile(sorted, 99)
+
+	return stats
+}
+
+// DnsSummaryResult 表示某个(domain, server, type, protocol)组合在一轮测试中的统计汇总
+type DnsSummaryResult struct {
+	Domain     string
+	Server     string
+	RecordType uint16
+	Protocol   string
+	Stats      Stats
+}
+
+// EnumerationResult 表示子域名枚举模式下一次成功解析的结果
+type EnumerationResult struct {
+	Word    string
+	FQDN    string
+	Server  string
+	Rcode   string
+	Answers string
+	Latency time.Duration
 }
 
 // 定义DnsWorkItem结构体用于并发处理
 type DnsWorkItem struct {
-	Domain    string
-	DNSServer string
+	Domain     string
+	DNSServer  string
+	RecordType uint16
+}
+
+// buildWorkItems 根据配置中的domains、query_types与dns_servers构造待测试的工作项列表。
+// domains条目可以通过"域名:类型"的形式指定单一记录类型，否则与query_types做叉乘，
+// 都未指定时默认查询A记录。
+func buildWorkItems(cfg *Config, logger *log.Logger) []DnsWorkItem {
+	items := make([]DnsWorkItem, 0, len(cfg.Domains)*len(cfg.DNSServers))
+	for _, domainEntry := range cfg.Domains {
+		domain, qtype, hasType := splitDomainAndType(domainEntry)
+
+		qtypes := []uint16{dns.TypeA}
+		if hasType {
+			qtypes = []uint16{qtype}
+		} else if len(cfg.QueryTypes) > 0 {
+			qtypes = make([]uint16, 0, len(cfg.QueryTypes))
+			for _, qt := range cfg.QueryTypes {
+				t, err := parseRecordType(qt)
+				if err != nil {
+					logger.Printf("域名: %s 配置的query_types条目 %q 无法识别，已忽略: %v\n", domain, qt, err)
+					continue
+				}
+				qtypes = append(qtypes, t)
+			}
+			if len(qtypes) == 0 {
+				logger.Printf("域名: %s 配置的query_types全部无法识别，回退为默认记录类型A\n", domain)
+				qtypes = []uint16{dns.TypeA}
+			}
+		}
+
+		for _, dnsServer := range cfg.DNSServers {
+			for _, t := range qtypes {
+				items = append(items, DnsWorkItem{Domain: domain, DNSServer: dnsServer, RecordType: t})
+			}
+		}
+	}
+	return items
 }
 
 type DnsTestContext struct {
-	Config         *Config
-	CSVFile        *os.File
-	CSVWriter      *csv.Writer
-	LogFile        *os.File
-	Logger         *log.Logger
-	TestRoundMutex sync.Mutex // 用于同步多轮测试的日志输出
+	Config               *Config
+	CSVFile              *os.File
+	CSVWriter            *csv.Writer
+	SummaryCSVFile       *os.File // 每(domain, server, type, protocol)组合的统计汇总CSV
+	SummaryCSVWriter     *csv.Writer
+	EnumerationCSVFile   *os.File // 子域名枚举模式下的结果CSV
+	EnumerationCSVWriter *csv.Writer
+	LogFile              *os.File
+	Logger               *log.Logger
+	TestRoundMutex       sync.Mutex // 用于同步多轮测试的日志输出
 }
 
 // loadConfig 加载配置文件
@@ -91,7 +408,7 @@ func (dt *DnsTestContext) initCSV(filename string) error {
 	dt.CSVWriter = csv.NewWriter(file)
 	defer dt.CSVWriter.Flush() // 这里保持不变，但在程序结束前也应确保Flush()
 
-	header := []string{"域名", "DNS服务器", "时间戳", "延时(毫秒)"}
+	header := []string{"域名", "DNS服务器", "记录类型", "协议", "AD", "AA", "ECS回显", "时间戳", "延时(毫秒)"}
 	if err := dt.CSVWriter.Write(header); err != nil {
 		return fmt.Errorf("写入CSV表头失败: %w", err)
 	}
@@ -99,11 +416,114 @@ func (dt *DnsTestContext) initCSV(filename string) error {
 	return nil
 }
 
+// initSummaryCSV 初始化统计汇总CSV
+func (dt *DnsTestContext) initSummaryCSV(filename string) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("创建/打开统计汇总CSV文件失败: %w", err)
+	}
+	dt.SummaryCSVFile = file
+
+	dt.SummaryCSVWriter = csv.NewWriter(file)
+	defer dt.SummaryCSVWriter.Flush()
+
+	header := []string{
+		"域名", "DNS服务器", "记录类型", "协议",
+		"最小延时(毫秒)", "最大延时(毫秒)", "平均延时(毫秒)", "标准差(毫秒)",
+		"P50(毫秒)", "P90(毫秒)", "P95(毫秒)", "P99(毫秒)",
+		"抖动(毫秒)", "丢包率", "尝试次数", "成功次数",
+	}
+	if err := dt.SummaryCSVWriter.Write(header); err != nil {
+		return fmt.Errorf("写入统计汇总CSV表头失败: %w", err)
+	}
+
+	return nil
+}
+
+// writeSummaryToCsv 将一轮中某个(domain, server, type, protocol)组合的统计汇总写入CSV
+func writeSummaryToCsv(dt *DnsTestContext, summary DnsSummaryResult) error {
+	durMs := func(d time.Duration) string {
+		return strconv.FormatFloat(d.Seconds()*1000, 'f', -1, 64)
+	}
+	record := []string{
+		summary.Domain,
+		summary.Server,
+		dns.TypeToString[summary.RecordType],
+		summary.Protocol,
+		durMs(summary.Stats.Min),
+		durMs(summary.Stats.Max),
+		durMs(summary.Stats.Mean),
+		durMs(summary.Stats.StdDev),
+		durMs(summary.Stats.P50),
+		durMs(summary.Stats.P90),
+		durMs(summary.Stats.P95),
+		durMs(summary.Stats.P99),
+		durMs(summary.Stats.Jitter),
+		strconv.FormatFloat(summary.Stats.LossRate, 'f', -1, 64),
+		strconv.Itoa(summary.Stats.Attempted),
+		strconv.Itoa(summary.Stats.Succeeded),
+	}
+
+	if err := dt.SummaryCSVWriter.Write(record); err != nil {
+		return fmt.Errorf("写入统计汇总CSV记录失败: %w", err)
+	}
+	return nil
+}
+
+// initEnumerationCSV 初始化子域名枚举结果CSV
+func (dt *DnsTestContext) initEnumerationCSV(filename string) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("创建/打开枚举结果CSV文件失败: %w", err)
+	}
+	dt.EnumerationCSVFile = file
+
+	dt.EnumerationCSVWriter = csv.NewWriter(file)
+	defer dt.EnumerationCSVWriter.Flush()
+
+	header := []string{"word", "fqdn", "server", "rcode", "answers", "latency_ms"}
+	if err := dt.EnumerationCSVWriter.Write(header); err != nil {
+		return fmt.Errorf("写入枚举结果CSV表头失败: %w", err)
+	}
+
+	return nil
+}
+
+// writeEnumerationToCsv 将一条子域名枚举结果写入CSV
+func writeEnumerationToCsv(dt *DnsTestContext, result EnumerationResult) error {
+	record := []string{
+		result.Word,
+		result.FQDN,
+		result.Server,
+		result.Rcode,
+		result.Answers,
+		strconv.FormatFloat(result.Latency.Seconds()*1000, 'f', -1, 64),
+	}
+
+	if err := dt.EnumerationCSVWriter.Write(record); err != nil {
+		return fmt.Errorf("写入枚举结果CSV记录失败: %w", err)
+	}
+	return nil
+}
+
 // writeResultToCsv 将结果保存到CSV
 func writeResultToCsv(dt *DnsTestContext, result DnsTestResult) error {
 	record := []string{
 		result.Domain,
 		result.Server,
+		dns.TypeToString[result.RecordType],
+		result.Protocol,
+		strconv.FormatBool(result.AD),
+		strconv.FormatBool(result.AA),
+		strconv.FormatBool(result.ECSEchoed),
 		result.Timestamp.Format(time.RFC3339),
 		strconv.FormatFloat(result.Latency.Seconds()*1000, 'f', -1, 64),
 	}
@@ -120,10 +540,20 @@ func NewDnsTestContext(config *Config) (*DnsTestContext, error) {
 		Config: config,
 	}
 	if config.SaveCsv {
-		filename := fmt.Sprintf("./dns_test_results/dns_test_%s.csv", time.Now().Format("2006-01-02_15-04-05"))
-		err := dt.initCSV(filename)
-		if err != nil {
-			return nil, err
+		if config.Mode == "enumerate" {
+			if err := dt.initEnumerationCSV("./dns_test_results/enumeration_results.csv"); err != nil {
+				return nil, err
+			}
+		} else {
+			timestamp := time.Now().Format("2006-01-02_15-04-05")
+			filename := fmt.Sprintf("./dns_test_results/dns_test_%s.csv", timestamp)
+			if err := dt.initCSV(filename); err != nil {
+				return nil, err
+			}
+			summaryFilename := fmt.Sprintf("./dns_test_results/dns_test_summary_%s.csv", timestamp)
+			if err := dt.initSummaryCSV(summaryFilename); err != nil {
+				return nil, err
+			}
 		}
 	}
 	if config.LogToFile {
@@ -140,38 +570,116 @@ func NewDnsTestContext(config *Config) (*DnsTestContext, error) {
 	return dt, nil
 }
 
-// StartAndRecordLatencies 执行指定次数的DNS查询并记录延迟结果。
-func StartAndRecordLatencies(domain, dnsServer string, count int, interval time.Duration, logger *log.Logger) (avgDelay time.Duration, delays []time.Duration, err error) {
-	delays = make([]time.Duration, 0, count)
-	for i := 1; i <= count; i++ {
-		_, latency, err := PerformDNSLookup(domain, dnsServer) // 丢弃响应信息，仅保留延时
+// QueryOutcome 记录单次查询的延时以及响应的关键标志位
+type QueryOutcome struct {
+	Latency   time.Duration
+	AD        bool
+	AA        bool
+	ECSEchoed bool
+}
+
+// StartAndRecordLatencies 执行指定次数的DNS查询并记录延迟结果，返回本次查询的统计信息。
+func StartAndRecordLatencies(domain, dnsServer string, qtype uint16, cfg *Config, logger *log.Logger) (stats Stats, outcomes []QueryOutcome, protocol string, err error) {
+	outcomes = make([]QueryOutcome, 0, cfg.TestCount)
+	delays := make([]time.Duration, 0, cfg.TestCount)
+	for i := 1; i <= cfg.TestCount; i++ {
+		reply, latency, proto, err := PerformDNSLookup(domain, dnsServer, qtype, cfg)
+		protocol = proto
 		if err != nil {
-			logger.Printf("域名: %s DNS: %s 第 %-2v 次 查询错误: %v\n", domain, dnsServer, i, err)
+			logger.Printf("域名: %s DNS: %s 记录类型: %s 第 %-2v 次 查询错误: %v\n", domain, dnsServer, dns.TypeToString[qtype], i, err)
 			continue
 		}
 		delays = append(delays, latency)
-		logger.Printf("域名: %s DNS: %s 第 %-2v 次 延时: %v\n", domain, dnsServer, i, latency)
-		time.Sleep(interval)
+		outcomes = append(outcomes, QueryOutcome{
+			Latency:   latency,
+			AD:        reply.AuthenticatedData,
+			AA:        reply.Authoritative,
+			ECSEchoed: ecsEchoedInReply(reply, cfg),
+		})
+		logger.Printf("域名: %s DNS: %s 记录类型: %s 协议: %s 第 %-2v 次 延时: %v\n", domain, dnsServer, dns.TypeToString[qtype], proto, i, latency)
+		time.Sleep(cfg.QueryInterval)
+	}
+
+	stats = computeStats(delays, cfg.TestCount)
+	return stats, outcomes, protocol, nil
+}
+
+// applyEdns0 为请求附加EDNS0选项：设置UDP缓冲区大小与DO（DNSSEC OK）位，
+// 并在配置了ecs_subnet时附加EDNS Client Subnet选项。
+func applyEdns0(req *dns.Msg, cfg *Config) error {
+	size := cfg.Edns0UDPSize
+	if size == 0 {
+		size = 4096
+	}
+	req.SetEdns0(size, cfg.DnssecOk)
+
+	if cfg.EcsSubnet == "" {
+		return nil
+	}
+
+	opt := req.IsEdns0()
+	if opt == nil {
+		return fmt.Errorf("SetEdns0后未能获取OPT记录")
+	}
+	ip, ipNet, err := net.ParseCIDR(cfg.EcsSubnet)
+	if err != nil {
+		return fmt.Errorf("解析ecs_subnet失败: %w", err)
 	}
+	ones, _ := ipNet.Mask.Size()
 
-	// 计算平均延时
-	avgDelay = calculateAverageDelay(delays)
-	return avgDelay, delays, nil
+	subnet := &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		SourceNetmask: uint8(ones),
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		subnet.Family = 1
+		subnet.Address = ip4
+	} else {
+		subnet.Family = 2
+		subnet.Address = ip.To16()
+	}
+	opt.Option = append(opt.Option, subnet)
+	return nil
 }
 
-// PerformDNSLookup 直接使用Go库实现DNS查询，返回响应消息和查询耗时。
-func PerformDNSLookup(domain, dnsServer string) (*dns.Msg, time.Duration, error) {
+// ecsEchoedInReply 判断响应中是否携带了ECS选项，用于检测resolver是否回显/剥离了ECS
+func ecsEchoedInReply(reply *dns.Msg, cfg *Config) bool {
+	if cfg.EcsSubnet == "" || reply == nil {
+		return false
+	}
+	opt := reply.IsEdns0()
+	if opt == nil {
+		return false
+	}
+	for _, o := range opt.Option {
+		if _, ok := o.(*dns.EDNS0_SUBNET); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// PerformDNSLookup 直接使用Go库实现DNS查询，返回响应消息、查询耗时和由detectProtocol推断的传输协议。
+func PerformDNSLookup(domain, dnsServer string, qtype uint16, cfg *Config) (*dns.Msg, time.Duration, string, error) {
+	protocol := detectProtocol(dnsServer, parseHTTPVersions(cfg.HTTPVersions))
+
+	bootstrap, err := buildBootstrapResolver(cfg)
+	if err != nil {
+		return nil, 0, protocol, fmt.Errorf("构建引导解析器失败: %w", err)
+	}
+
 	opts := &upstream.Options{
 		Timeout:            5 * time.Second, // 超时时间设置为 5S
-		InsecureSkipVerify: false,
-		HTTPVersions:       []upstream.HTTPVersion{upstream.HTTPVersion11},
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		HTTPVersions:       parseHTTPVersions(cfg.HTTPVersions),
+		Bootstrap:          bootstrap,
 	} // 根据实际需求调整上游选项
 
 	startTime := time.Now()
 
 	u, err := upstream.AddressToUpstream(dnsServer, opts)
 	if err != nil {
-		return nil, 0, fmt.Errorf("创建上游服务失败: %w", err)
+		return nil, 0, protocol, fmt.Errorf("创建上游服务失败: %w", err)
 	}
 
 	req := &dns.Msg{}
@@ -179,76 +687,526 @@ func PerformDNSLookup(domain, dnsServer string) (*dns.Msg, time.Duration, error)
 	req.Id = dns.Id()
 	req.RecursionDesired = true
 	req.Question = []dns.Question{
-		{Name: domain + ".", Qtype: dns.TypeA, Qclass: dns.ClassINET},
+		{Name: domain + ".", Qtype: qtype, Qclass: dns.ClassINET},
+	}
+	if err := applyEdns0(req, cfg); err != nil {
+		return nil, 0, protocol, err
 	}
 
 	reply, err := u.Exchange(req)
 	if err != nil {
-		return nil, 0, fmt.Errorf("执行DNS查询失败: %w", err)
+		return nil, 0, protocol, fmt.Errorf("执行DNS查询失败: %w", err)
 	}
 
 	elapsed := time.Since(startTime)
 
-	return reply, elapsed, nil
-}
-
-// calculateAverageDelay 计算延迟数组的平均值。
-func calculateAverageDelay(delays []time.Duration) time.Duration {
-	var sum time.Duration
-	for _, delay := range delays {
-		sum += delay
-	}
-	return sum / time.Duration(len(delays))
+	return reply, elapsed, protocol, nil
 }
 
 // performTestRound 函数处理一轮测试
-func performTestRound(dt *DnsTestContext, round int) error {
-	workQueue := make(chan DnsWorkItem, len(dt.Config.Domains)*len(dt.Config.DNSServers))
+func performTestRound(dt *DnsTestContext, round int) ([]DnsSummaryResult, error) {
+	workItems := buildWorkItems(dt.Config, dt.Logger)
+	workQueue := make(chan DnsWorkItem, len(workItems))
 	var wg sync.WaitGroup
+
+	var resultsMutex sync.Mutex
+	var roundResults []DnsTestResult
+	var summaryResults []DnsSummaryResult
+
 	wg.Add(dt.Config.Concurrency)
 	for i := 0; i < dt.Config.Concurrency; i++ {
 		go func() {
 			defer wg.Done()
 
 			for work := range workQueue {
-				avgDelay, delays, err := StartAndRecordLatencies(work.Domain, work.DNSServer, dt.Config.TestCount, dt.Config.QueryInterval, dt.Logger)
+				stats, outcomes, protocol, err := StartAndRecordLatencies(work.Domain, work.DNSServer, work.RecordType, dt.Config, dt.Logger)
 				if err != nil {
 					dt.Logger.Printf("处理域名: %s 和 DNS: %s 时出错: %v\n", work.Domain, work.DNSServer, err)
 					continue
 				}
 
-				dt.Logger.Printf("域名: %s DNS: %s 平均延时: %.2fms\n", work.Domain, work.DNSServer, avgDelay.Seconds()*1000)
+				dt.Logger.Printf("域名: %s DNS: %s 记录类型: %s 协议: %s 平均延时: %.2fms 丢包率: %.2f%%\n", work.Domain, work.DNSServer, dns.TypeToString[work.RecordType], protocol, stats.Mean.Seconds()*1000, stats.LossRate*100)
+
+				summary := DnsSummaryResult{
+					Domain:     work.Domain,
+					Server:     work.DNSServer,
+					RecordType: work.RecordType,
+					Protocol:   protocol,
+					Stats:      stats,
+				}
+
+				resultsMutex.Lock()
+				for _, outcome := range outcomes {
+					roundResults = append(roundResults, DnsTestResult{
+						Domain:     work.Domain,
+						Server:     work.DNSServer,
+						RecordType: work.RecordType,
+						Protocol:   protocol,
+						AD:         outcome.AD,
+						AA:         outcome.AA,
+						ECSEchoed:  outcome.ECSEchoed,
+						Timestamp:  time.Now(),
+						Latency:    outcome.Latency,
+					})
+				}
+				summaryResults = append(summaryResults, summary)
+				resultsMutex.Unlock()
 
 				if dt.Config.SaveCsv {
-					for _, delay := range delays {
+					for _, outcome := range outcomes {
 						result := DnsTestResult{
-							Domain:    work.Domain,
-							Server:    work.DNSServer,
-							Timestamp: time.Now(),
-							Latency:   delay,
+							Domain:     work.Domain,
+							Server:     work.DNSServer,
+							RecordType: work.RecordType,
+							Protocol:   protocol,
+							AD:         outcome.AD,
+							AA:         outcome.AA,
+							ECSEchoed:  outcome.ECSEchoed,
+							Timestamp:  time.Now(),
+							Latency:    outcome.Latency,
 						}
 						if err := writeResultToCsv(dt, result); err != nil {
 							dt.Logger.Printf("将测试结果写入CSV文件失败: %v\n", err)
 						}
 					}
+					if err := writeSummaryToCsv(dt, summary); err != nil {
+						dt.Logger.Printf("将统计汇总写入CSV文件失败: %v\n", err)
+					}
 				}
 			}
 		}()
 	}
 
-	for _, domain := range dt.Config.Domains {
-		for _, dnsServer := range dt.Config.DNSServers {
-			workQueue <- DnsWorkItem{Domain: domain, DNSServer: dnsServer}
-		}
+	for _, item := range workItems {
+		workQueue <- item
 	}
 	close(workQueue) // 关闭工作队列
 
 	wg.Wait() // 等待所有goroutine完成任务
 
+	logProtocolComparison(dt, roundResults)
+	logSummaryTable(dt, summaryResults)
+
 	dt.TestRoundMutex.Lock()
 	dt.Logger.Printf("第 %d 轮测试完成。\n", round)
 	dt.TestRoundMutex.Unlock()
 
+	return summaryResults, nil
+}
+
+// logSummaryTable 按P95从低到高打印本轮每个(domain, server, type, protocol)组合的统计汇总
+func logSummaryTable(dt *DnsTestContext, summaries []DnsSummaryResult) {
+	if len(summaries) == 0 {
+		return
+	}
+	sorted := make([]DnsSummaryResult, len(summaries))
+	copy(sorted, summaries)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Stats.P95 < sorted[j].Stats.P95 })
+
+	dt.Logger.Println("延时统计汇总 (按P95升序):")
+	for _, s := range sorted {
+		dt.Logger.Printf("  域名: %s DNS: %s 类型: %s 协议: %s 最小: %.2fms 最大: %.2fms 平均: %.2fms 标准差: %.2fms P50: %.2fms P90: %.2fms P95: %.2fms P99: %.2fms 抖动: %.2fms 丢包率: %.2f%%\n",
+			s.Domain, s.Server, dns.TypeToString[s.RecordType], s.Protocol,
+			s.Stats.Min.Seconds()*1000, s.Stats.Max.Seconds()*1000, s.Stats.Mean.Seconds()*1000, s.Stats.StdDev.Seconds()*1000,
+			s.Stats.P50.Seconds()*1000, s.Stats.P90.Seconds()*1000, s.Stats.P95.Seconds()*1000, s.Stats.P99.Seconds()*1000,
+			s.Stats.Jitter.Seconds()*1000, s.Stats.LossRate*100)
+	}
+}
+
+// logProtocolComparison 按DNS服务器与协议对本轮结果做平均延时汇总，便于对比明文与加密传输的差异
+func logProtocolComparison(dt *DnsTestContext, results []DnsTestResult) {
+	type key struct {
+		server   string
+		protocol string
+	}
+	sums := make(map[key]time.Duration)
+	counts := make(map[key]int)
+	for _, r := range results {
+		k := key{server: r.Server, protocol: r.Protocol}
+		sums[k] += r.Latency
+		counts[k]++
+	}
+	if len(counts) == 0 {
+		return
+	}
+
+	dt.Logger.Println("协议对比 (DNS服务器 | 协议 | 平均延时):")
+	for k, count := range counts {
+		avg := sums[k] / time.Duration(count)
+		dt.Logger.Printf("  %s | %s | %.2fms\n", k.server, k.protocol, avg.Seconds()*1000)
+	}
+}
+
+// loadWordlist 读取字典文件，每行一个标签，忽略空行与以#开头的注释行
+func loadWordlist(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开字典文件: %w", err)
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		words = append(words, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取字典文件失败: %w", err)
+	}
+	return words, nil
+}
+
+// randomNonceLabel 生成一个随机标签，用于探测泛解析（wildcard）resolver
+func randomNonceLabel() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成随机标签失败: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// formatAnswers 将DNS响应的Answer记录格式化为单个字符串，多条记录用" | "分隔
+func formatAnswers(msg *dns.Msg) string {
+	if msg == nil || len(msg.Answer) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(msg.Answer))
+	for _, rr := range msg.Answer {
+		parts = append(parts, rr.String())
+	}
+	return strings.Join(parts, " | ")
+}
+
+// rrSignature 返回一条资源记录去除TTL后的文本表示，TTL会随查询时刻的缓存剩余时间变化，
+// 不应影响"这是否是同一条记录"的判断。
+func rrSignature(rr dns.RR) string {
+	copied := dns.Copy(rr)
+	copied.Header().Ttl = 0
+	return copied.String()
+}
+
+// answerSignature 将响应的Answer记录转换为忽略TTL差异与返回顺序的签名字符串，
+// 用于判断两次查询是否返回了相同的记录集合（例如泛解析探测与真实枚举查询的对比，
+// 或轮询/负载均衡下同一记录集合以不同顺序返回的情况）。
+func answerSignature(msg *dns.Msg) string {
+	if msg == nil || len(msg.Answer) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(msg.Answer))
+	for _, rr := range msg.Answer {
+		parts = append(parts, rrSignature(rr))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " | ")
+}
+
+// rcodeString 返回DNS响应的Rcode文本表示
+func rcodeString(msg *dns.Msg) string {
+	if msg == nil {
+		return ""
+	}
+	return dns.RcodeToString[msg.Rcode]
+}
+
+// detectWildcardAnswers 通过查询一个随机nonce标签探测resolver是否存在泛解析，
+// 返回该resolver对应的泛解析应答签名集合（不含TTL），枚举阶段命中此集合的结果会被过滤掉。
+func detectWildcardAnswers(cfg *Config, baseDomain, server string, qtypes []uint16) (map[string]bool, error) {
+	nonce, err := randomNonceLabel()
+	if err != nil {
+		return nil, err
+	}
+	fqdn := nonce + "." + baseDomain
+
+	wildcard := make(map[string]bool)
+	for _, qtype := range qtypes {
+		msg, _, _, err := PerformDNSLookup(fqdn, server, qtype, cfg)
+		if err != nil {
+			continue
+		}
+		if sig := answerSignature(msg); sig != "" {
+			wildcard[sig] = true
+		}
+	}
+	return wildcard, nil
+}
+
+// enumerationWorkItem 子域名枚举模式下的单个工作项
+type enumerationWorkItem struct {
+	Word      string
+	DNSServer string
+}
+
+// enumerationDiscovery 记录一次真实解析到的枚举结果，用于枚举结束后按(子域名,类型)
+// 跨服务器比对，发现分光/过滤等resolver间的解析差异。
+type enumerationDiscovery struct {
+	Word      string
+	QType     uint16
+	Server    string
+	Signature string
+}
+
+// logEnumerationDiscrepancies 按(子域名,记录类型)分组，比较不同DNS服务器返回的应答签名，
+// 对存在差异的分组输出报告，用于发现分光解析(split-horizon)或做了过滤的resolver。
+// 只比较返回了非泛解析记录的服务器；未返回记录、查询出错或只命中泛解析的服务器不参与比较。
+func logEnumerationDiscrepancies(dt *DnsTestContext, discoveries []enumerationDiscovery) {
+	type key struct {
+		word  string
+		qtype uint16
+	}
+	bySignature := make(map[key]map[string][]string)
+	for _, d := range discoveries {
+		k := key{word: d.Word, qtype: d.QType}
+		if bySignature[k] == nil {
+			bySignature[k] = make(map[string][]string)
+		}
+		bySignature[k][d.Signature] = append(bySignature[k][d.Signature], d.Server)
+	}
+
+	reported := false
+	for k, sigServers := range bySignature {
+		if len(sigServers) < 2 {
+			continue // 返回了记录的服务器应答一致，不存在差异
+		}
+		if !reported {
+			dt.Logger.Println("枚举差异报告 (不同DNS服务器对同一子域名返回了不同应答，可能为分光或过滤):")
+			reported = true
+		}
+		dt.Logger.Printf("  子域名: %s.%s 类型: %s\n", k.word, dt.Config.EnumerateBaseDomain, dns.TypeToString[k.qtype])
+		for sig, servers := range sigServers {
+			dt.Logger.Printf("    应答: %s -> 服务器: %s\n", sig, strings.Join(servers, ", "))
+		}
+	}
+}
+
+// performEnumerationRound 执行一轮子域名枚举：对字典中的每个标签与每个resolver的组合
+// 查询配置的记录类型，过滤掉命中泛解析的应答，并记录真实解析到的结果。
+func performEnumerationRound(dt *DnsTestContext, round int) error {
+	qtypes := make([]uint16, 0, len(dt.Config.EnumerateTypes))
+	for _, t := range dt.Config.EnumerateTypes {
+		qt, err := parseRecordType(t)
+		if err != nil {
+			continue
+		}
+		qtypes = append(qtypes, qt)
+	}
+	if len(qtypes) == 0 {
+		qtypes = []uint16{dns.TypeA, dns.TypeAAAA, dns.TypeCNAME}
+	}
+
+	words, err := loadWordlist(dt.Config.WordlistFile)
+	if err != nil {
+		return fmt.Errorf("加载字典文件失败: %w", err)
+	}
+
+	wildcards := make(map[string]map[string]bool, len(dt.Config.DNSServers))
+	for _, server := range dt.Config.DNSServers {
+		wc, err := detectWildcardAnswers(dt.Config, dt.Config.EnumerateBaseDomain, server, qtypes)
+		if err != nil {
+			dt.Logger.Printf("DNS: %s 泛解析探测失败: %v\n", server, err)
+			wc = make(map[string]bool)
+		}
+		wildcards[server] = wc
+	}
+
+	workQueue := make(chan enumerationWorkItem, len(words)*len(dt.Config.DNSServers))
+	var wg sync.WaitGroup
+	var discoveriesMutex sync.Mutex
+	var discoveries []enumerationDiscovery
+	wg.Add(dt.Config.Concurrency)
+	for i := 0; i < dt.Config.Concurrency; i++ {
+		go func() {
+			defer wg.Done()
+
+			for work := range workQueue {
+				fqdn := work.Word + "." + dt.Config.EnumerateBaseDomain
+				for _, qtype := range qtypes {
+					msg, latency, _, err := PerformDNSLookup(fqdn, work.DNSServer, qtype, dt.Config)
+					if err != nil {
+						dt.Logger.Printf("枚举: %s DNS: %s 类型: %s 查询错误: %v\n", fqdn, work.DNSServer, dns.TypeToString[qtype], err)
+						continue
+					}
+
+					signature := answerSignature(msg)
+					if signature == "" || wildcards[work.DNSServer][signature] {
+						continue // 未解析到记录，或命中了泛解析应答
+					}
+					answers := formatAnswers(msg)
+
+					dt.Logger.Printf("枚举发现: %s DNS: %s 类型: %s 延时: %v 应答: %s\n", fqdn, work.DNSServer, dns.TypeToString[qtype], latency, answers)
+
+					discoveriesMutex.Lock()
+					discoveries = append(discoveries, enumerationDiscovery{
+						Word:      work.Word,
+						QType:     qtype,
+						Server:    work.DNSServer,
+						Signature: signature,
+					})
+					discoveriesMutex.Unlock()
+
+					if dt.Config.SaveCsv {
+						result := EnumerationResult{
+							Word:    work.Word,
+							FQDN:    fqdn,
+							Server:  work.DNSServer,
+							Rcode:   rcodeString(msg),
+							Answers: answers,
+							Latency: latency,
+						}
+						if err := writeEnumerationToCsv(dt, result); err != nil {
+							dt.Logger.Printf("写入枚举结果CSV失败: %v\n", err)
+						}
+					}
+				}
+			}
+		}()
+	}
+
+	for _, word := range words {
+		for _, server := range dt.Config.DNSServers {
+			workQueue <- enumerationWorkItem{Word: word, DNSServer: server}
+		}
+	}
+	close(workQueue)
+
+	wg.Wait()
+
+	logEnumerationDiscrepancies(dt, discoveries)
+
+	dt.TestRoundMutex.Lock()
+	dt.Logger.Printf("第 %d 轮子域名枚举完成。\n", round)
+	dt.TestRoundMutex.Unlock()
+
+	return nil
+}
+
+// ServerRanking 表示排名结果中的一个条目，用于序列化为YAML/Markdown
+type ServerRanking struct {
+	Rank        int     `yaml:"rank"`
+	Server      string  `yaml:"server"`
+	Metric      string  `yaml:"metric"`
+	MetricMs    float64 `yaml:"metric_value_ms"`
+	LossRate    float64 `yaml:"loss_rate"`
+	SampleCount int     `yaml:"sample_count"`
+}
+
+// normalizeSelectionMetric 规范化配置的指标名，未识别的值一律回退为"p95"，
+// 确保排名结果中展示的指标名与实际参与排序计算的指标一致。
+func normalizeSelectionMetric(metric string) string {
+	switch strings.ToLower(strings.TrimSpace(metric)) {
+	case "mean", "p50", "p90", "p99":
+		return strings.ToLower(strings.TrimSpace(metric))
+	default:
+		return "p95"
+	}
+}
+
+// selectionMetricValue 根据规范化后的指标名从Stats中取出对应的延时值
+func selectionMetricValue(stats Stats, metric string) time.Duration {
+	switch metric {
+	case "mean":
+		return stats.Mean
+	case "p50":
+		return stats.P50
+	case "p90":
+		return stats.P90
+	case "p99":
+		return stats.P99
+	default:
+		return stats.P95
+	}
+}
+
+// rankServers 将跨所有轮次、域名与记录类型收集到的统计汇总按DNS服务器聚合，
+// 过滤掉丢包率超过max_loss的服务器，并按配置的指标从低到高排序，截取前top_n个。
+func rankServers(summaries []DnsSummaryResult, sel *SelectionConfig) []ServerRanking {
+	type aggregate struct {
+		sumMetric time.Duration
+		sumLoss   float64
+		count     int
+	}
+	metric := normalizeSelectionMetric(sel.Metric)
+
+	aggregates := make(map[string]*aggregate)
+	for _, s := range summaries {
+		a, ok := aggregates[s.Server]
+		if !ok {
+			a = &aggregate{}
+			aggregates[s.Server] = a
+		}
+		a.sumMetric += selectionMetricValue(s.Stats, metric)
+		a.sumLoss += s.Stats.LossRate
+		a.count++
+	}
+
+	rankings := make([]ServerRanking, 0, len(aggregates))
+	for server, a := range aggregates {
+		avgLoss := a.sumLoss / float64(a.count)
+		if sel.MaxLoss != nil && avgLoss > *sel.MaxLoss {
+			continue
+		}
+		avgMetric := a.sumMetric / time.Duration(a.count)
+		rankings = append(rankings, ServerRanking{
+			Server:      server,
+			Metric:      metric,
+			MetricMs:    avgMetric.Seconds() * 1000,
+			LossRate:    avgLoss,
+			SampleCount: a.count,
+		})
+	}
+
+	sort.Slice(rankings, func(i, j int) bool { return rankings[i].MetricMs < rankings[j].MetricMs })
+
+	topN := sel.TopN
+	if topN <= 0 || topN > len(rankings) {
+		topN = len(rankings)
+	}
+	rankings = rankings[:topN]
+	for i := range rankings {
+		rankings[i].Rank = i + 1
+	}
+
+	return rankings
+}
+
+// writeSelectionOutputs 将排名结果写入配置的YAML文件，并额外生成一份同名的Markdown表格
+func writeSelectionOutputs(sel *SelectionConfig, rankings []ServerRanking) error {
+	outputFile := sel.Output
+	if outputFile == "" {
+		outputFile = "best_servers.yaml"
+	}
+	if dir := filepath.Dir(outputFile); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("创建目录失败: %w", err)
+		}
+	}
+
+	data, err := yaml.Marshal(rankings)
+	if err != nil {
+		return fmt.Errorf("序列化排名结果失败: %w", err)
+	}
+	if err := os.WriteFile(outputFile, data, 0644); err != nil {
+		return fmt.Errorf("写入排名结果文件失败: %w", err)
+	}
+
+	metric := "p95"
+	if len(rankings) > 0 {
+		metric = rankings[0].Metric
+	}
+	mdFile := strings.TrimSuffix(outputFile, filepath.Ext(outputFile)) + ".md"
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("| 排名 | DNS服务器 | %s(毫秒) | 丢包率 | 样本数 |\n", metric))
+	sb.WriteString("|---|---|---|---|---|\n")
+	for _, r := range rankings {
+		sb.WriteString(fmt.Sprintf("| %d | %s | %.2f | %.2f%% | %d |\n", r.Rank, r.Server, r.MetricMs, r.LossRate*100, r.SampleCount))
+	}
+	if err := os.WriteFile(mdFile, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("写入排名结果Markdown文件失败: %w", err)
+	}
+
 	return nil
 }
 
@@ -259,19 +1217,45 @@ func main() {
 		log.Fatalf("加载配置文件失败: %v", err)
 	}
 
+	var allSummaries []DnsSummaryResult
+
 	for round := 1; round <= cfg.TestRounds; round++ {
 		dt, err := NewDnsTestContext(cfg)
 		if err != nil {
 			log.Fatalf("初始化应用程序上下文失败: %v", err)
 		}
-		defer dt.CSVFile.Close()
 		defer dt.LogFile.Close()
-		if err := performTestRound(dt, round); err != nil {
+
+		if cfg.Mode == "enumerate" {
+			defer dt.EnumerationCSVFile.Close()
+			if err := performEnumerationRound(dt, round); err != nil {
+				dt.Logger.Printf("执行第 %d 轮子域名枚举时出错: %v\n", round, err)
+			}
+			if dt.Config.SaveCsv {
+				dt.EnumerationCSVWriter.Flush()
+			}
+			continue
+		}
+
+		defer dt.CSVFile.Close()
+		defer dt.SummaryCSVFile.Close()
+		summaries, err := performTestRound(dt, round)
+		if err != nil {
 			dt.Logger.Printf("执行第 %d 轮测试时出错: %v\n", round, err)
 		}
+		allSummaries = append(allSummaries, summaries...)
 		// 在程序结束前强制刷新缓存中的数据到CSV文件（如果启用）
 		if dt.Config.SaveCsv {
 			dt.CSVWriter.Flush()
+			dt.SummaryCSVWriter.Flush()
+		}
+	}
+
+	// 所有轮次结束后，根据selection配置对DNS服务器做聚合排名并输出结果文件
+	if cfg.Mode != "enumerate" && cfg.Selection != nil {
+		rankings := rankServers(allSummaries, cfg.Selection)
+		if err := writeSelectionOutputs(cfg.Selection, rankings); err != nil {
+			log.Printf("写入服务器排名结果失败: %v\n", err)
 		}
 	}
 }